@@ -14,9 +14,11 @@
 package main
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"regexp"
 	"strconv"
@@ -39,6 +41,7 @@ type consumerCmd struct {
 	ack         bool
 	raw         bool
 	destination string
+	destStream  string
 	inputFile   string
 
 	maxDeliver    int
@@ -77,13 +80,13 @@ func configureConsumerCommand(app *kingpin.Application) {
 		f.Flag("target", "Push based delivery target subject").StringVar(&c.delivery)
 		f.Flag("filter", "Filter Stream by subjects").Default("_unset_").StringVar(&c.filterSubject)
 		f.Flag("replay", "Replay Policy (instant, original)").EnumVar(&c.replayPolicy, "instant", "original")
-		f.Flag("deliver", "Start policy (all, new, last, 1h, msg sequence)").StringVar(&c.startPolicy)
+		f.Flag("deliver", "Start policy (all, new, last, 1h, msg sequence), sequences before the first message in the Stream start at the first message").StringVar(&c.startPolicy)
 		f.Flag("ack", "Acknowledgement policy (none, all, explicit)").StringVar(&c.ackPolicy)
 		f.Flag("wait", "Acknowledgement waiting time").Default("-1s").DurationVar(&c.ackWait)
 		f.Flag("sample", "Percentage of requests to sample for monitoring purposes").Default("-1").IntVar(&c.samplePct)
 		f.Flag("ephemeral", "Create an ephemeral Consumer").Default("false").BoolVar(&c.ephemeral)
 		f.Flag("pull", "Deliver messages in 'pull' mode").BoolVar(&c.pull)
-		f.Flag("max-deliver", "Maximum amount of times a message will be delivered").IntVar(&c.maxDeliver)
+		f.Flag("max-deliver", "Maximum amount of times a message will be delivered, -1 for unlimited").IntVar(&c.maxDeliver)
 	}
 
 	consAdd := cons.Command("add", "Creates a new Consumer").Alias("create").Alias("new").Action(c.createAction)
@@ -97,6 +100,7 @@ func configureConsumerCommand(app *kingpin.Application) {
 	consCp.Arg("stream", "Stream name").Required().StringVar(&c.stream)
 	consCp.Arg("source", "Source Consumer name").Required().StringVar(&c.consumer)
 	consCp.Arg("destination", "Destination Consumer name").Required().StringVar(&c.destination)
+	consCp.Flag("destination-stream", "Stream to create the new Consumer on, defaults to the source Stream").StringVar(&c.destStream)
 	addCreateFlags(consCp)
 
 	consNext := cons.Command("next", "Retrieves messages from Pull Consumers without interactive prompts").Action(c.nextAction)
@@ -209,11 +213,20 @@ func (c *consumerCmd) showInfo(config api.ConsumerConfig, state api.ConsumerInfo
 		fmt.Printf("            Ack Wait: %v\n", config.AckWait)
 	}
 	fmt.Printf("       Replay Policy: %s\n", config.ReplayPolicy.String())
-	if config.MaxDeliver != -1 {
-		fmt.Printf("  Maximum Deliveries: %d\n", config.MaxDeliver)
+	if config.AckPolicy != api.AckNone {
+		if config.MaxDeliver == -1 {
+			fmt.Println("  Maximum Deliveries: unlimited")
+		} else {
+			fmt.Printf("  Maximum Deliveries: %d\n", config.MaxDeliver)
+		}
 	}
 	if config.SampleFrequency != "" {
-		fmt.Printf("       Sampling Rate: %s\n", config.SampleFrequency)
+		pct, err := parseSamplePercent(config.SampleFrequency)
+		if err != nil {
+			fmt.Printf("       Sampling Rate: %s\n", config.SampleFrequency)
+		} else {
+			fmt.Printf("       Sampling Rate: %d%%\n", pct)
+		}
 	}
 
 	fmt.Println()
@@ -289,6 +302,10 @@ func (c *consumerCmd) setStartPolicy(cfg *api.ConsumerConfig, policy string) {
 		return
 	}
 
+	// the last policy set should fully win so clear any earlier start position
+	cfg.OptStartSeq = 0
+	cfg.OptStartTime = nil
+
 	if policy == "all" {
 		cfg.DeliverPolicy = api.DeliverAll
 	} else if policy == "last" {
@@ -308,6 +325,142 @@ func (c *consumerCmd) setStartPolicy(cfg *api.ConsumerConfig, policy string) {
 	}
 }
 
+// deliverPolicyName maps p to the words used by the --deliver flag
+func deliverPolicyName(p api.DeliverPolicy) string {
+	switch p {
+	case api.DeliverAll:
+		return "all"
+	case api.DeliverLast:
+		return "last"
+	case api.DeliverNew:
+		return "new"
+	case api.DeliverByStartSequence:
+		return "sequence"
+	case api.DeliverByStartTime:
+		return "time"
+	default:
+		return fmt.Sprintf("unknown (%d)", p)
+	}
+}
+
+// validateDurableName ensures name is usable as a single subject token
+func validateDurableName(name string) error {
+	if ok, _ := regexp.MatchString(`\.|\*|>|\s`, name); ok {
+		return fmt.Errorf("durable name can not contain '.', '*', '>' or white space")
+	}
+
+	return nil
+}
+
+// validateConsumerConfig catches conflicting settings locally that the server would otherwise reject with less context
+func validateConsumerConfig(cfg *api.ConsumerConfig) error {
+	err := validateDurableName(cfg.Durable)
+	if err != nil {
+		return err
+	}
+
+	switch cfg.DeliverPolicy {
+	case api.DeliverByStartSequence:
+		if cfg.OptStartSeq == 0 {
+			return fmt.Errorf("deliver policy %q requires a start sequence", deliverPolicyName(cfg.DeliverPolicy))
+		}
+	case api.DeliverByStartTime:
+		if cfg.OptStartTime == nil || cfg.OptStartTime.IsZero() {
+			return fmt.Errorf("deliver policy %q requires a start time", deliverPolicyName(cfg.DeliverPolicy))
+		}
+	}
+
+	if cfg.OptStartSeq != 0 && cfg.DeliverPolicy != api.DeliverByStartSequence {
+		return fmt.Errorf("start sequence %d can not be combined with deliver policy %q", cfg.OptStartSeq, deliverPolicyName(cfg.DeliverPolicy))
+	}
+
+	if cfg.OptStartTime != nil && !cfg.OptStartTime.IsZero() && cfg.DeliverPolicy != api.DeliverByStartTime {
+		return fmt.Errorf("start time %v can not be combined with deliver policy %q", cfg.OptStartTime, deliverPolicyName(cfg.DeliverPolicy))
+	}
+
+	if cfg.DeliverSubject == "" && cfg.AckPolicy != api.AckExplicit {
+		return fmt.Errorf("pull based Consumers require the explicit ack policy, got %s", cfg.AckPolicy)
+	}
+
+	if cfg.AckPolicy == api.AckNone && cfg.MaxDeliver > 0 {
+		return fmt.Errorf("maximum deliveries can not be set with ack policy %s", cfg.AckPolicy)
+	}
+
+	// 0 lets the server pick its default while -1 is the unlimited sentinel
+	if cfg.MaxDeliver < -1 {
+		return fmt.Errorf("maximum deliveries must be -1 for unlimited or greater than 0, got %d", cfg.MaxDeliver)
+	}
+
+	if cfg.FilterSubject != "" {
+		err = validateSubject(cfg.FilterSubject)
+		if err != nil {
+			return fmt.Errorf("invalid filter subject: %s", err)
+		}
+	}
+
+	return nil
+}
+
+// validateConsumerForStream checks cfg against the configuration of the Stream it will be created on
+func validateConsumerForStream(cfg *api.ConsumerConfig, stream api.StreamConfig) error {
+	subjects := stream.Subjects
+	if len(subjects) == 0 {
+		subjects = []string{stream.Name}
+	}
+
+	if cfg.FilterSubject != "" {
+		matched := false
+		for _, subject := range subjects {
+			if subjectIsSubset(cfg.FilterSubject, subject) {
+				matched = true
+				break
+			}
+		}
+
+		if !matched {
+			return fmt.Errorf("filter subject %q does not match any of the Stream subjects %s", cfg.FilterSubject, strings.Join(subjects, ", "))
+		}
+	}
+
+	// delivering into a subject the Stream consumes would store every delivery again and deliver it again
+	if cfg.DeliverSubject != "" {
+		for _, subject := range subjects {
+			if subjectsOverlap(cfg.DeliverSubject, subject) {
+				return fmt.Errorf("delivery subject %q would be consumed by the Stream subject %q creating a delivery loop", cfg.DeliverSubject, subject)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (c *consumerCmd) validateForStream(cfg *api.ConsumerConfig) {
+	stream, err := jsm.LoadStream(c.stream)
+	kingpin.FatalIfError(err, "could not load Stream %s", c.stream)
+
+	info, err := stream.LatestInformation()
+	kingpin.FatalIfError(err, "could not load Stream %s", c.stream)
+
+	err = validateConsumerForStream(cfg, info.Config)
+	kingpin.FatalIfError(err, "invalid Consumer configuration")
+
+	requested := cfg.OptStartSeq
+	if clampStartSequence(cfg, info.State.FirstSeq) {
+		fmt.Printf("Start sequence %d is before the first message in Stream %s, earlier messages have been purged or expired, starting at sequence %d\n\n", requested, c.stream, cfg.OptStartSeq)
+	}
+}
+
+// clampStartSequence moves a sequence start policy that is before first up to first, messages before it were purged or expired
+func clampStartSequence(cfg *api.ConsumerConfig, first uint64) bool {
+	if cfg.DeliverPolicy != api.DeliverByStartSequence || cfg.OptStartSeq >= first {
+		return false
+	}
+
+	cfg.OptStartSeq = first
+
+	return true
+}
+
 func (c *consumerCmd) cpAction(pc *kingpin.ParseContext) (err error) {
 	c.connectAndSetup(true, false)
 
@@ -316,6 +469,16 @@ func (c *consumerCmd) cpAction(pc *kingpin.ParseContext) (err error) {
 
 	cfg := source.Configuration()
 
+	if c.destStream != "" && c.destStream != c.stream {
+		c.stream = c.destStream
+
+		// sequences are specific to the source Stream
+		if cfg.DeliverPolicy == api.DeliverByStartSequence {
+			cfg.DeliverPolicy = api.DeliverAll
+			cfg.OptStartSeq = 0
+		}
+	}
+
 	if c.ackWait > 0 {
 		cfg.AckWait = c.ackWait
 	}
@@ -345,6 +508,9 @@ func (c *consumerCmd) cpAction(pc *kingpin.ParseContext) (err error) {
 
 	if c.ackPolicy != "" {
 		cfg.AckPolicy = c.ackPolicyFromString(c.ackPolicy)
+		if cfg.AckPolicy == api.AckNone {
+			cfg.MaxDeliver = -1
+		}
 	}
 
 	if c.filterSubject != "_unset_" {
@@ -359,20 +525,41 @@ func (c *consumerCmd) cpAction(pc *kingpin.ParseContext) (err error) {
 		cfg.MaxDeliver = c.maxDeliver
 	}
 
+	err = validateConsumerConfig(&cfg)
+	kingpin.FatalIfError(err, "invalid Consumer configuration")
+
+	c.validateForStream(&cfg)
+
 	consumer, err := jsm.NewConsumerFromDefault(c.stream, cfg)
 	kingpin.FatalIfError(err, "Consumer creation failed")
 
-	if cfg.Durable == "" {
-		return nil
-	}
-
-	c.consumer = cfg.Durable
+	// ephemeral Consumers get their name from the server
+	c.consumer = consumer.Name()
 
 	c.showConsumer(consumer)
 
 	return nil
 }
 
+// parseConsumerConfig decodes a single JSON Consumer configuration, unknown fields are rejected so that typos in stored configuration are not silently ignored
+func parseConsumerConfig(data []byte) (*api.ConsumerConfig, error) {
+	cfg := &api.ConsumerConfig{}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+
+	err := dec.Decode(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	if dec.Decode(&json.RawMessage{}) != io.EOF {
+		return nil, fmt.Errorf("unexpected data after the configuration")
+	}
+
+	return cfg, nil
+}
+
 func (c *consumerCmd) prepareConfig() (cfg *api.ConsumerConfig, err error) {
 	cfg = c.defaultConsumer()
 
@@ -382,14 +569,16 @@ func (c *consumerCmd) prepareConfig() (cfg *api.ConsumerConfig, err error) {
 			return nil, err
 		}
 
-		cfg = &api.ConsumerConfig{}
-		err = json.Unmarshal(f, cfg)
+		cfg, err = parseConsumerConfig(f)
+		if err != nil {
+			return nil, fmt.Errorf("invalid Consumer configuration in %s: %s", c.inputFile, err)
+		}
 
 		if cfg.Durable != "" && c.consumer != "" && cfg.Durable != c.consumer {
 			return cfg, fmt.Errorf("non durable consumer name in %s does not match CLI consumer name %s", c.inputFile, c.consumer)
 		}
 
-		return cfg, err
+		return cfg, nil
 	}
 
 	if c.consumer == "" && !c.ephemeral {
@@ -401,9 +590,8 @@ func (c *consumerCmd) prepareConfig() (cfg *api.ConsumerConfig, err error) {
 	}
 	cfg.Durable = c.consumer
 
-	if ok, _ := regexp.MatchString(`\.|\*|>`, cfg.Durable); ok {
-		kingpin.Fatalf("durable name can not contain '.', '*', '>'")
-	}
+	err = validateDurableName(cfg.Durable)
+	kingpin.FatalIfError(err, "invalid Consumer name")
 
 	if !c.pull && c.delivery == "" {
 		err = survey.AskOne(&survey.Input{
@@ -427,7 +615,7 @@ func (c *consumerCmd) prepareConfig() (cfg *api.ConsumerConfig, err error) {
 	if c.startPolicy == "" {
 		err = survey.AskOne(&survey.Input{
 			Message: "Start policy (all, new, last, 1h, msg sequence)",
-			Help:    "This controls how the Consumer starts out, does it make all messages available, only the latest, ones after a certain time or time sequence. Settable using --deliver",
+			Help:    "This controls how the Consumer starts out, does it make all messages available, only the latest, ones after a certain time or time sequence. A sequence before the first message in the Stream starts at the first message as earlier ones were purged or expired. Settable using --deliver",
 		}, &c.startPolicy, survey.WithValidator(survey.Required))
 		kingpin.FatalIfError(err, "could not request start policy")
 	}
@@ -531,12 +719,20 @@ func (c *consumerCmd) createAction(pc *kingpin.ParseContext) (err error) {
 			kingpin.Fatalf("Validation Failed: %s", strings.Join(errs, "\n\t"))
 		}
 
+		err = validateConsumerConfig(cfg)
+		kingpin.FatalIfError(err, "Validation Failed")
+
 		fmt.Println("Configuration is a valid Consumer")
 		return nil
 	}
 
+	err = validateConsumerConfig(cfg)
+	kingpin.FatalIfError(err, "invalid Consumer configuration")
+
 	c.connectAndSetup(true, false)
 
+	c.validateForStream(cfg)
+
 	created, err := jsm.NewConsumerFromDefault(c.stream, *cfg)
 	kingpin.FatalIfError(err, "Consumer creation failed")
 
@@ -0,0 +1,186 @@
+// Copyright 2020 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nats-io/jsm.go/api"
+)
+
+func TestValidateConsumerConfig(t *testing.T) {
+	cfg := pull1Cons()
+	err := validateConsumerConfig(&cfg)
+	checkErr(t, err, "valid config failed validation: %v", err)
+
+	cfg = pull1Cons()
+	cfg.OptStartSeq = 10
+	err = validateConsumerConfig(&cfg)
+	if err == nil || err.Error() != `start sequence 10 can not be combined with deliver policy "all"` {
+		t.Fatalf("expected start sequence with deliver all to fail, got %v", err)
+	}
+
+	cfg = pull1Cons()
+	cfg.DeliverPolicy = api.DeliverByStartSequence
+	err = validateConsumerConfig(&cfg)
+	if err == nil || err.Error() != `deliver policy "sequence" requires a start sequence` {
+		t.Fatalf("expected by start sequence without a sequence to fail, got %v", err)
+	}
+
+	cfg = pull1Cons()
+	cfg.DeliverPolicy = api.DeliverByStartTime
+	err = validateConsumerConfig(&cfg)
+	if err == nil || err.Error() != `deliver policy "time" requires a start time` {
+		t.Fatalf("expected by start time without a time to fail, got %v", err)
+	}
+
+	start := time.Now()
+	cfg.OptStartTime = &start
+	err = validateConsumerConfig(&cfg)
+	checkErr(t, err, "valid by start time config failed validation: %v", err)
+
+	cfg.DeliverPolicy = api.DeliverLast
+	err = validateConsumerConfig(&cfg)
+	if err == nil || !strings.HasSuffix(err.Error(), `can not be combined with deliver policy "last"`) {
+		t.Fatalf("expected start time with deliver last to fail, got %v", err)
+	}
+
+	cfg = pull1Cons()
+	cfg.AckPolicy = api.AckAll
+	if validateConsumerConfig(&cfg) == nil {
+		t.Fatalf("expected pull consumer with ack all to fail")
+	}
+
+	cfg = pull1Cons()
+	cfg.DeliverSubject = "out"
+	cfg.AckPolicy = api.AckNone
+	cfg.MaxDeliver = 10
+	if validateConsumerConfig(&cfg) == nil {
+		t.Fatalf("expected max deliver with ack none to fail")
+	}
+
+	cfg = pull1Cons()
+	cfg.MaxDeliver = -1
+	err = validateConsumerConfig(&cfg)
+	checkErr(t, err, "unlimited max deliver failed validation: %v", err)
+
+	cfg.MaxDeliver = -2
+	if validateConsumerConfig(&cfg) == nil {
+		t.Fatalf("expected max deliver below -1 to fail")
+	}
+
+	cfg = pull1Cons()
+	cfg.Durable = "push 1"
+	if validateConsumerConfig(&cfg) == nil {
+		t.Fatalf("expected durable name with white space to fail")
+	}
+
+	cfg = pull1Cons()
+	cfg.FilterSubject = "js.>.mem"
+	if validateConsumerConfig(&cfg) == nil {
+		t.Fatalf("expected invalid filter subject to fail")
+	}
+}
+
+func TestConsumerSetStartPolicy(t *testing.T) {
+	c := &consumerCmd{}
+	cfg := pull1Cons()
+
+	for _, policy := range []string{"10", "1h", "last", "20", "all"} {
+		c.setStartPolicy(&cfg, policy)
+	}
+
+	if cfg.DeliverPolicy != api.DeliverAll {
+		t.Fatalf("expected deliver all policy got %v", cfg.DeliverPolicy)
+	}
+
+	if cfg.OptStartSeq != 0 || cfg.OptStartTime != nil {
+		t.Fatalf("expected start sequence and time to be cleared: %#v", cfg)
+	}
+
+	c.setStartPolicy(&cfg, "1h")
+	c.setStartPolicy(&cfg, "20")
+
+	if cfg.DeliverPolicy != api.DeliverByStartSequence || cfg.OptStartSeq != 20 || cfg.OptStartTime != nil {
+		t.Fatalf("expected only start sequence 20 to be set: %#v", cfg)
+	}
+}
+
+func TestParseConsumerConfig(t *testing.T) {
+	valid := `{"durable_name": "pull1", "deliver_policy": "all", "ack_policy": "explicit", "replay_policy": "instant"}`
+
+	cfg, err := parseConsumerConfig([]byte(valid + "\n"))
+	checkErr(t, err, "could not parse valid config: %v", err)
+	if cfg.Durable != "pull1" {
+		t.Fatalf("expected durable pull1 got %q", cfg.Durable)
+	}
+
+	_, err = parseConsumerConfig([]byte(`{"durable_name": "pull1", "delivery_subject": "out"}`))
+	if err == nil || !strings.Contains(err.Error(), "delivery_subject") {
+		t.Fatalf("expected unknown field delivery_subject to fail, got %v", err)
+	}
+
+	for _, extra := range []string{valid, "junk"} {
+		_, err = parseConsumerConfig([]byte(valid + extra))
+		if err == nil {
+			t.Fatalf("expected trailing %q to fail", extra)
+		}
+	}
+}
+
+func TestValidateConsumerForStream(t *testing.T) {
+	cfg := pull1Cons()
+	cfg.FilterSubject = "js.mem.1"
+	err := validateConsumerForStream(&cfg, mem1Stream())
+	checkErr(t, err, "valid filter subject failed validation: %v", err)
+
+	for _, filter := range []string{"js.file.1", "js.>", "js.*"} {
+		cfg.FilterSubject = filter
+		if validateConsumerForStream(&cfg, mem1Stream()) == nil {
+			t.Fatalf("expected filter subject %q outside the stream subjects to fail", filter)
+		}
+	}
+
+	cfg = pull1Cons()
+	cfg.DeliverSubject = "out.mem1"
+	err = validateConsumerForStream(&cfg, mem1Stream())
+	checkErr(t, err, "valid delivery subject failed validation: %v", err)
+
+	cfg.DeliverSubject = "js.mem.out"
+	if validateConsumerForStream(&cfg, mem1Stream()) == nil {
+		t.Fatalf("expected delivery subject consumed by the stream to fail")
+	}
+}
+
+func TestClampStartSequence(t *testing.T) {
+	cfg := pull1Cons()
+	cfg.DeliverPolicy = api.DeliverByStartSequence
+	cfg.OptStartSeq = 1
+
+	if !clampStartSequence(&cfg, 10) || cfg.OptStartSeq != 10 {
+		t.Fatalf("expected start sequence 1 to be clamped to 10 got %d", cfg.OptStartSeq)
+	}
+
+	cfg.OptStartSeq = 20
+	if clampStartSequence(&cfg, 10) || cfg.OptStartSeq != 20 {
+		t.Fatalf("expected start sequence 20 to be unchanged got %d", cfg.OptStartSeq)
+	}
+
+	cfg = pull1Cons()
+	if clampStartSequence(&cfg, 10) || cfg.OptStartSeq != 0 {
+		t.Fatalf("expected deliver all policy to be unchanged got %d", cfg.OptStartSeq)
+	}
+}
@@ -23,6 +23,7 @@ import (
 	"os/exec"
 	"path/filepath"
 	"reflect"
+	"regexp"
 	"strings"
 	"testing"
 	"time"
@@ -383,6 +384,61 @@ func TestCLIConsumerInfo(t *testing.T) {
 	}
 }
 
+func TestCLIConsumerCopyEphemeral(t *testing.T) {
+	srv, nc := setupConsTest(t)
+	defer srv.Shutdown()
+
+	push := pull1Cons()
+	push.DeliverSubject = "out.push1"
+	_, err := jsm.NewConsumerFromDefault("mem1", push)
+	checkErr(t, err, "could not create consumer: %v", err)
+	consumerShouldExist(t, "mem1", "push1")
+
+	// keep interest on the target so the ephemeral Consumer is not removed before it is checked
+	sub, err := nc.SubscribeSync("out.eph")
+	checkErr(t, err, "could not subscribe: %v", err)
+	defer sub.Unsubscribe()
+	nc.Flush()
+
+	out := runNatsCli(t, fmt.Sprintf("--server='%s' con cp mem1 push1 eph --ephemeral --target out.eph", srv.ClientURL()))
+
+	matches := regexp.MustCompile(`Information for Consumer mem1 > (\S+)`).FindSubmatch(out)
+	if len(matches) != 2 {
+		t.Fatalf("expected the generated Consumer name in the output, got: %s", out)
+	}
+
+	name := string(matches[1])
+	if name == "eph" || name == "push1" {
+		t.Fatalf("expected a server generated Consumer name, got %s", name)
+	}
+	consumerShouldExist(t, "mem1", name)
+
+	cons, err := jsm.LoadConsumer("mem1", name)
+	checkErr(t, err, "could not load consumer: %v", err)
+	if cons.Configuration().Durable != "" {
+		t.Fatalf("expected %s to be ephemeral", name)
+	}
+	if cons.DeliverySubject() != "out.eph" {
+		t.Fatalf("expected delivery subject out.eph got %s", cons.DeliverySubject())
+	}
+}
+
+func TestCLIConsumerInfoUnlimitedDeliveries(t *testing.T) {
+	srv, _ := setupConsTest(t)
+	defer srv.Shutdown()
+
+	cfg := pull1Cons()
+	cfg.MaxDeliver = -1
+	_, err := jsm.NewConsumerFromDefault("mem1", cfg)
+	checkErr(t, err, "could not create consumer: %v", err)
+	consumerShouldExist(t, "mem1", "push1")
+
+	out := runNatsCli(t, fmt.Sprintf("--server='%s' con info mem1 push1", srv.ClientURL()))
+	if !strings.Contains(string(out), "Maximum Deliveries: unlimited") {
+		t.Fatalf("expected unlimited maximum deliveries in cli output: %v", string(out))
+	}
+}
+
 func TestCLIConsumerLs(t *testing.T) {
 	srv, _ := setupConsTest(t)
 	defer srv.Shutdown()
@@ -545,6 +601,89 @@ func TestCLIConsumerCopy(t *testing.T) {
 	}
 }
 
+func TestCLIConsumerAddPurgedStartSequence(t *testing.T) {
+	srv, nc := setupConsTest(t)
+	defer srv.Shutdown()
+
+	for i := 0; i < 2; i++ {
+		_, err := nc.Request("js.mem.1", []byte("hello"), time.Second)
+		checkErr(t, err, "could not publish message: %v", err)
+	}
+
+	stream, err := jsm.LoadStream("mem1")
+	checkErr(t, err, "could not load stream: %v", err)
+	checkErr(t, stream.Purge(), "could not purge stream")
+
+	first := streamInfo(t, "mem1").State.FirstSeq
+
+	out := runNatsCli(t, fmt.Sprintf("--server='%s' con add mem1 pull1 --replay instant --deliver 1 --pull --filter '' --max-deliver 20", srv.ClientURL()))
+	if !strings.Contains(string(out), fmt.Sprintf("starting at sequence %d", first)) {
+		t.Fatalf("expected a notice about the clamped start sequence, got: %s", out)
+	}
+
+	pull1, err := jsm.LoadConsumer("mem1", "pull1")
+	checkErr(t, err, "could not load consumer: %v", err)
+	if pull1.Configuration().OptStartSeq != first {
+		t.Fatalf("expected start sequence %d got %d", first, pull1.Configuration().OptStartSeq)
+	}
+}
+
+func TestCLIConsumerCopyAckNone(t *testing.T) {
+	srv, _ := setupConsTest(t)
+	defer srv.Shutdown()
+
+	source := pull1Cons()
+	source.DeliverSubject = "out.push1"
+	source.MaxDeliver = 20
+	_, err := jsm.NewConsumerFromDefault("mem1", source)
+	checkErr(t, err, "could not create consumer: %v", err)
+	consumerShouldExist(t, "mem1", "push1")
+
+	runNatsCli(t, fmt.Sprintf("--server='%s' con cp mem1 push1 push2 --ack none", srv.ClientURL()))
+	consumerShouldExist(t, "mem1", "push2")
+
+	push2, err := jsm.LoadConsumer("mem1", "push2")
+	checkErr(t, err, "could not load consumer: %v", err)
+	cfg := push2.Configuration()
+	if cfg.AckPolicy != api.AckNone || cfg.MaxDeliver != -1 {
+		t.Fatalf("expected ack none with unlimited deliveries got %v with %d", cfg.AckPolicy, cfg.MaxDeliver)
+	}
+}
+
+func TestCLIConsumerCopyToStream(t *testing.T) {
+	srv, _ := setupConsTest(t)
+	defer srv.Shutdown()
+
+	_, err := jsm.NewStreamFromDefault("file1", file1Stream())
+	checkErr(t, err, "could not create stream: %v", err)
+	streamShouldExist(t, "file1")
+
+	source := pull1Cons()
+	source.DeliverPolicy = api.DeliverByStartSequence
+	source.OptStartSeq = 10
+	_, err = jsm.NewConsumerFromDefault("mem1", source)
+	checkErr(t, err, "could not create consumer: %v", err)
+	consumerShouldExist(t, "mem1", "push1")
+
+	runNatsCli(t, fmt.Sprintf("--server='%s' con cp mem1 push1 pull1 --pull --destination-stream file1", srv.ClientURL()))
+	consumerShouldExist(t, "file1", "pull1")
+
+	// sequences do not carry over between Streams so the copy starts from the beginning
+	pull1, err := jsm.LoadConsumer("file1", "pull1")
+	checkErr(t, err, "could not load consumer: %v", err)
+	cfg := pull1.Configuration()
+	if cfg.DeliverPolicy != api.DeliverAll || cfg.OptStartSeq != 0 {
+		t.Fatalf("expected deliver all with start sequence 0 got %s with %d", deliverPolicyName(cfg.DeliverPolicy), cfg.OptStartSeq)
+	}
+
+	ols, err := jsm.ConsumerNames("mem1")
+	checkErr(t, err, "could not get consumer: %v", err)
+
+	if len(ols) != 1 {
+		t.Fatalf("expected 1 consumer on mem1, got %d", len(ols))
+	}
+}
+
 func TestCLIBackupRestore(t *testing.T) {
 	srv, _ := setupConsTest(t)
 	defer srv.Shutdown()
@@ -222,6 +222,80 @@ func parseDurationString(dstr string) (dur time.Duration, err error) {
 	return dur, nil
 }
 
+// parseSamplePercent parses a Consumer sample frequency given as either "50" or "50%"
+func parseSamplePercent(s string) (int, error) {
+	pct, err := strconv.Atoi(strings.TrimSuffix(strings.TrimSpace(s), "%"))
+	if err != nil {
+		return 0, fmt.Errorf("invalid sample frequency %q", s)
+	}
+
+	if pct < 0 || pct > 100 {
+		return 0, fmt.Errorf("sample frequency %q is not between 0 and 100", s)
+	}
+
+	return pct, nil
+}
+
+// validateSubject checks that subject is made up of non empty tokens without whitespace and that wildcards are full tokens with > only in the last position
+func validateSubject(subject string) error {
+	tokens := strings.Split(subject, ".")
+
+	for i, token := range tokens {
+		switch {
+		case token == "":
+			return fmt.Errorf("subject %q has an empty token", subject)
+		case strings.IndexFunc(token, unicode.IsSpace) != -1:
+			return fmt.Errorf("subject %q may not contain white space", subject)
+		case token == ">" && i != len(tokens)-1:
+			return fmt.Errorf("subject %q may only have > as the last token", subject)
+		case token != "*" && token != ">" && strings.ContainsAny(token, "*>"):
+			return fmt.Errorf("subject %q may only use wildcards as full tokens", subject)
+		}
+	}
+
+	return nil
+}
+
+// subjectIsSubset determines if every subject matched by subject is also matched by of, wildcards are supported on either side
+func subjectIsSubset(subject string, of string) bool {
+	st := strings.Split(subject, ".")
+	ot := strings.Split(of, ".")
+
+	for i := 0; i < len(ot); i++ {
+		switch {
+		case ot[i] == ">":
+			return i < len(st)
+		case i >= len(st) || st[i] == ">":
+			return false
+		case ot[i] == "*" || st[i] == ot[i]:
+			continue
+		default:
+			return false
+		}
+	}
+
+	return len(st) == len(ot)
+}
+
+// subjectsOverlap determines if any subject could match both a and b, wildcards are supported on either side
+func subjectsOverlap(a string, b string) bool {
+	at := strings.Split(a, ".")
+	bt := strings.Split(b, ".")
+
+	for i := 0; i < len(at) && i < len(bt); i++ {
+		switch {
+		case at[i] == ">" || bt[i] == ">":
+			return true
+		case at[i] == "*" || bt[i] == "*" || at[i] == bt[i]:
+			continue
+		default:
+			return false
+		}
+	}
+
+	return len(at) == len(bt)
+}
+
 func askConfirmation(prompt string, dflt bool) (bool, error) {
 	ans := dflt
 
@@ -80,3 +80,62 @@ func TestParseDurationString(t *testing.T) {
 		t.Fatalf("expected 1.1 hour from 1.1h duration, got %v", d)
 	}
 }
+
+func TestParseSamplePercent(t *testing.T) {
+	for _, s := range []string{"50", "50%", " 50% "} {
+		pct, err := parseSamplePercent(s)
+		checkErr(t, err, "failed to parse %q: %s", s, err)
+		if pct != 50 {
+			t.Fatalf("expected 50 from %q, got %d", s, pct)
+		}
+	}
+
+	for _, s := range []string{"", "%", "fifty", "101%", "-1"} {
+		_, err := parseSamplePercent(s)
+		if err == nil {
+			t.Fatalf("expected %q to fail but it did not", s)
+		}
+	}
+}
+
+func TestValidateSubject(t *testing.T) {
+	for _, s := range []string{"a", "a.b", "a.*.c", "a.>", ">", "*"} {
+		err := validateSubject(s)
+		checkErr(t, err, "expected %q to be valid: %s", s, err)
+	}
+
+	for _, s := range []string{"", "a..b", ".a", "a.", "a b", "a.>.b", "a.b*", "a.>b"} {
+		err := validateSubject(s)
+		if err == nil {
+			t.Fatalf("expected %q to be invalid but it was not", s)
+		}
+	}
+}
+
+func TestSubjectIsSubset(t *testing.T) {
+	for _, s := range [][2]string{{"a.b", "a.b"}, {"a.b", "a.*"}, {"a.b.c", "a.>"}, {"a.*.c", "a.>"}, {"a.>", "a.>"}, {"a.*", "*.*"}, {"a.b", ">"}} {
+		if !subjectIsSubset(s[0], s[1]) {
+			t.Fatalf("expected %q to be a subset of %q", s[0], s[1])
+		}
+	}
+
+	for _, s := range [][2]string{{"a.*", "a.b"}, {"a.>", "a.b.>"}, {"a.*", "a.b.>"}, {"a.>", "a.*"}, {"a", "a.>"}, {"a.b.c", "a.*"}, {"b.c", "a.>"}} {
+		if subjectIsSubset(s[0], s[1]) {
+			t.Fatalf("expected %q not to be a subset of %q", s[0], s[1])
+		}
+	}
+}
+
+func TestSubjectsOverlap(t *testing.T) {
+	for _, s := range [][2]string{{"a.b", "a.b"}, {"a.b", "a.*"}, {"a.*", "a.b"}, {"a.b.c", "a.>"}, {"a.*.c", "a.b.*"}, {">", "a.b"}} {
+		if !subjectsOverlap(s[0], s[1]) {
+			t.Fatalf("expected %q and %q to overlap", s[0], s[1])
+		}
+	}
+
+	for _, s := range [][2]string{{"a.b", "a.c"}, {"a.b", "a.b.c"}, {"a.*", "a.b.c"}, {"a", "a.>"}, {"b.>", "a.>"}} {
+		if subjectsOverlap(s[0], s[1]) {
+			t.Fatalf("expected %q and %q not to overlap", s[0], s[1])
+		}
+	}
+}